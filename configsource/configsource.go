@@ -0,0 +1,101 @@
+// Package configsource lets the controller load Functions,
+// HTTPTriggers, Environments and other fission resources from sources
+// other than the Kubernetes API, following the same pattern as the
+// kubelet's PodConfig: a directory of YAML manifests, an HTTP
+// endpoint, and a git repository can all feed resources in alongside
+// the apiserver, enabling GitOps-style function deployment without a
+// separate operator.
+//
+// Each Source polls its origin and emits Events on a shared channel.
+// Events are keyed by (source, namespace, name) so the controller can
+// reconcile file/URL/git-managed resources against CRD-managed ones
+// without the two clobbering each other; reconciled objects are
+// annotated with fission.io/source so their origin stays visible.
+package configsource
+
+import "log"
+
+// SourceAnnotation is set on every resource the controller creates on
+// behalf of a non-CRD Source, recording where it came from.
+const SourceAnnotation = "fission.io/source"
+
+// EventType is the kind of change a Source observed.
+type EventType string
+
+const (
+	EventAdd    EventType = "ADD"
+	EventUpdate EventType = "UPDATE"
+	EventDelete EventType = "DELETE"
+)
+
+// Key identifies a resource independently of which Source manages it.
+type Key struct {
+	Source    string
+	Namespace string
+	Name      string
+}
+
+// Event describes a single resource change observed by a Source.
+type Event struct {
+	Type   EventType
+	Key    Key
+	Kind   string      // e.g. "Function", "HTTPTrigger", "Environment"
+	Object interface{} // the manifest's raw YAML bytes; nil for DELETE events and for URLSource events
+}
+
+// Source polls an origin for fission resources and publishes changes
+// to a merge channel.
+type Source interface {
+	// Name identifies this source, used as Key.Source and in the
+	// fission.io/source annotation, e.g. "file", "url", "git".
+	Name() string
+
+	// Start begins polling and delivering Events to out until stopCh
+	// is closed. A poll error (a network blip, a malformed manifest)
+	// must be logged and retried on the next tick, not treated as
+	// fatal: returning here takes this source permanently offline,
+	// since Merger.Start only logs an early return rather than
+	// restarting it.
+	Start(stopCh <-chan struct{}, out chan<- Event) error
+}
+
+// Merger fans the Events from several Sources into one channel that
+// the controller's reconcile loop consumes.
+type Merger struct {
+	sources []Source
+	out     chan Event
+}
+
+// NewMerger creates a Merger over sources, buffering up to
+// queueSize pending events.
+func NewMerger(queueSize int, sources ...Source) *Merger {
+	return &Merger{
+		sources: sources,
+		out:     make(chan Event, queueSize),
+	}
+}
+
+// Events returns the channel the controller should range over to
+// reconcile incoming resources.
+func (m *Merger) Events() <-chan Event {
+	return m.out
+}
+
+// Start runs every configured Source until stopCh is closed. A Source
+// is expected to retry transient poll failures on its own rather than
+// returning; if one does return early anyway, Start logs it and keeps
+// the other sources running rather than tearing all of them down, so
+// one broken source can't silently stop reconciling the rest.
+func (m *Merger) Start(stopCh <-chan struct{}) error {
+	for _, source := range m.sources {
+		go func(source Source) {
+			err := source.Start(stopCh, m.out)
+			if err != nil {
+				log.Printf("configsource: %v source exited: %v", source.Name(), err)
+			}
+		}(source)
+	}
+
+	<-stopCh
+	return nil
+}
@@ -0,0 +1,106 @@
+package configsource
+
+import (
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// FileSource polls a directory of YAML manifests on disk and emits an
+// Event for each Function/HTTPTrigger/Environment/etc. found, diffing
+// against what it saw on the previous poll to decide ADD/UPDATE/DELETE.
+type FileSource struct {
+	name      string
+	dir       string
+	frequency time.Duration
+
+	seen map[Key]string // key -> manifest content, to detect deletes/updates
+}
+
+// NewFileSource creates a FileSource polling dir every frequency (e.g.
+// the value of --configFileFrequency).
+func NewFileSource(dir string, frequency time.Duration) *FileSource {
+	return &FileSource{
+		name:      "file",
+		dir:       dir,
+		frequency: frequency,
+		seen:      make(map[Key]string),
+	}
+}
+
+// Name implements Source.
+func (f *FileSource) Name() string {
+	return f.name
+}
+
+// Start implements Source, retrying a malformed manifest or transient
+// filesystem error on the next tick rather than returning.
+func (f *FileSource) Start(stopCh <-chan struct{}, out chan<- Event) error {
+	ticker := time.NewTicker(f.frequency)
+	defer ticker.Stop()
+
+	f.pollAndLog(out)
+
+	for {
+		select {
+		case <-stopCh:
+			return nil
+		case <-ticker.C:
+			f.pollAndLog(out)
+		}
+	}
+}
+
+func (f *FileSource) pollAndLog(out chan<- Event) {
+	if err := f.poll(out); err != nil {
+		log.Printf("configsource: file source poll of %v failed, will retry: %v", f.dir, err)
+	}
+}
+
+func (f *FileSource) poll(out chan<- Event) error {
+	matches, err := filepath.Glob(filepath.Join(f.dir, "*.yaml"))
+	if err != nil {
+		return err
+	}
+
+	current := make(map[Key]string, len(matches))
+	for _, path := range matches {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		var manifest struct {
+			Kind     string `yaml:"kind"`
+			Metadata struct {
+				Namespace string `yaml:"namespace"`
+				Name      string `yaml:"name"`
+			} `yaml:"metadata"`
+		}
+		if err := yaml.Unmarshal(content, &manifest); err != nil {
+			return err
+		}
+
+		key := Key{Source: f.Name(), Namespace: manifest.Metadata.Namespace, Name: manifest.Metadata.Name}
+		hash := string(content)
+		current[key] = hash
+
+		if prev, ok := f.seen[key]; !ok {
+			out <- Event{Type: EventAdd, Key: key, Kind: manifest.Kind, Object: content}
+		} else if prev != hash {
+			out <- Event{Type: EventUpdate, Key: key, Kind: manifest.Kind, Object: content}
+		}
+	}
+
+	for key := range f.seen {
+		if _, ok := current[key]; !ok {
+			out <- Event{Type: EventDelete, Key: key}
+		}
+	}
+	f.seen = current
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package configsource
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeManifest(t *testing.T, dir, filename, namespace, name string, extra string) {
+	t.Helper()
+	content := "kind: Function\nmetadata:\n  namespace: " + namespace + "\n  name: " + name + "\n" + extra
+	if err := ioutil.WriteFile(filepath.Join(dir, filename), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func drainEvent(t *testing.T, out chan Event) Event {
+	t.Helper()
+	select {
+	case ev := <-out:
+		return ev
+	case <-time.After(time.Second):
+		t.Fatal("expected an event, got none")
+		return Event{}
+	}
+}
+
+func assertNoEvent(t *testing.T, out chan Event) {
+	t.Helper()
+	select {
+	case ev := <-out:
+		t.Fatalf("expected no event, got %+v", ev)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// TestFileSourcePollDiffing exercises the add/update/delete diffing
+// poll() does by comparing the current directory listing against what
+// it saw last time.
+func TestFileSourcePollDiffing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "configsource-file-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := NewFileSource(dir, time.Second)
+	out := make(chan Event, 10)
+
+	writeManifest(t, dir, "a.yaml", "default", "fn-a", "")
+	if err := src.poll(out); err != nil {
+		t.Fatalf("initial poll: %v", err)
+	}
+	ev := drainEvent(t, out)
+	if ev.Type != EventAdd || ev.Key.Name != "fn-a" {
+		t.Fatalf("got %+v, want an ADD for fn-a", ev)
+	}
+	assertNoEvent(t, out)
+
+	// Re-polling with nothing changed must not emit anything.
+	if err := src.poll(out); err != nil {
+		t.Fatalf("unchanged poll: %v", err)
+	}
+	assertNoEvent(t, out)
+
+	// Changing the manifest's content (same key) emits an UPDATE.
+	writeManifest(t, dir, "a.yaml", "default", "fn-a", "# bumped\n")
+	if err := src.poll(out); err != nil {
+		t.Fatalf("update poll: %v", err)
+	}
+	ev = drainEvent(t, out)
+	if ev.Type != EventUpdate || ev.Key.Name != "fn-a" {
+		t.Fatalf("got %+v, want an UPDATE for fn-a", ev)
+	}
+	assertNoEvent(t, out)
+
+	// Removing the file emits a DELETE.
+	if err := os.Remove(filepath.Join(dir, "a.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.poll(out); err != nil {
+		t.Fatalf("delete poll: %v", err)
+	}
+	ev = drainEvent(t, out)
+	if ev.Type != EventDelete || ev.Key.Name != "fn-a" {
+		t.Fatalf("got %+v, want a DELETE for fn-a", ev)
+	}
+	assertNoEvent(t, out)
+}
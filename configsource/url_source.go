@@ -0,0 +1,121 @@
+package configsource
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// URLSource polls an HTTP endpoint that serves a multi-document YAML
+// stream of fission resources, the same shape FileSource reads from
+// disk.
+type URLSource struct {
+	url       string
+	frequency time.Duration
+	client    *http.Client
+
+	seen map[Key]string
+}
+
+// NewURLSource creates a URLSource polling url every frequency (e.g.
+// the value of --configURLFrequency).
+func NewURLSource(url string, frequency time.Duration) *URLSource {
+	return &URLSource{
+		url:       url,
+		frequency: frequency,
+		client:    http.DefaultClient,
+		seen:      make(map[Key]string),
+	}
+}
+
+// Name implements Source.
+func (u *URLSource) Name() string {
+	return "url"
+}
+
+// Start implements Source, retrying a network blip or non-200 response
+// on the next tick rather than returning.
+func (u *URLSource) Start(stopCh <-chan struct{}, out chan<- Event) error {
+	ticker := time.NewTicker(u.frequency)
+	defer ticker.Stop()
+
+	u.pollAndLog(out)
+
+	for {
+		select {
+		case <-stopCh:
+			return nil
+		case <-ticker.C:
+			u.pollAndLog(out)
+		}
+	}
+}
+
+func (u *URLSource) pollAndLog(out chan<- Event) {
+	if err := u.poll(out); err != nil {
+		log.Printf("configsource: url source poll of %v failed, will retry: %v", u.url, err)
+	}
+}
+
+func (u *URLSource) poll(out chan<- Event) error {
+	resp, err := u.client.Get(u.url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// Bail out without touching u.seen: treating a transient
+		// error response as "the manifest stream is now empty" would
+		// emit a DELETE for every resource this source has ever seen.
+		return fmt.Errorf("GET %v: %v", u.url, resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(body))
+	current := make(map[Key]string)
+	for {
+		var manifest struct {
+			Kind     string `yaml:"kind"`
+			Metadata struct {
+				Namespace string `yaml:"namespace"`
+				Name      string `yaml:"name"`
+			} `yaml:"metadata"`
+		}
+		if err := decoder.Decode(&manifest); err != nil {
+			if err == io.EOF {
+				break
+			}
+			// Bail out without committing current: a malformed
+			// document partway through the stream must not be
+			// mistaken for "every resource after it was deleted".
+			return fmt.Errorf("decoding manifest from %v: %w", u.url, err)
+		}
+
+		key := Key{Source: u.Name(), Namespace: manifest.Metadata.Namespace, Name: manifest.Metadata.Name}
+		current[key] = manifest.Kind
+
+		if _, ok := u.seen[key]; !ok {
+			out <- Event{Type: EventAdd, Key: key, Kind: manifest.Kind}
+		}
+	}
+
+	for key := range u.seen {
+		if _, ok := current[key]; !ok {
+			out <- Event{Type: EventDelete, Key: key}
+		}
+	}
+	u.seen = current
+
+	return nil
+}
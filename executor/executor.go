@@ -0,0 +1,192 @@
+// Package executor specializes function pods on demand and tells the
+// router where to send requests for them. Which backend specializes a
+// given function is keyed off its Environment's runtime: this package
+// adds the wasm backend (a shared pool of wasmtime/wasmer pods, see
+// the wasm subpackage) alongside poolmgr/newdeploy.
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/fission/fission"
+	"github.com/fission/fission/executor/wasm"
+	"github.com/fission/fission/health"
+)
+
+// RuntimeWasm is the Environment runtime value that routes a function
+// to the wasm backend instead of poolmgr/newdeploy.
+const RuntimeWasm = "wasm"
+
+// wasmPoolCapacity is the most wasm host pods the pool creates, on
+// demand; loaded and warm hosts both count against it. Unlike
+// poolmgr's per-language generalized pools,
+// there's only one wasm runtime image, so a single pool suffices.
+const wasmPoolCapacity = 8
+
+// Backend specializes a function and returns the URL the router
+// should send its requests to. poolmgr and newdeploy aren't part of
+// this snapshot; wasmBackend is the one concrete implementation here.
+type Backend interface {
+	GetServiceForFunction(functionName string, module wasm.Module) (string, error)
+}
+
+// wasmBackend adapts wasm.Pool to Backend, specializing a pooled host
+// pod with the function's compiled module on first request.
+type wasmBackend struct {
+	pool *wasm.Pool
+}
+
+func newWasmBackend(newHost func() (wasm.Host, error)) *wasmBackend {
+	return &wasmBackend{
+		pool: wasm.NewPool(wasmPoolCapacity, newHost),
+	}
+}
+
+// GetServiceForFunction implements Backend.
+func (b *wasmBackend) GetServiceForFunction(functionName string, module wasm.Module) (string, error) {
+	module.Name = functionName
+	host, err := b.pool.GetHost(module)
+	if err != nil {
+		return "", fmt.Errorf("specializing wasm function %v: %w", functionName, err)
+	}
+	return host.URL(), nil
+}
+
+// Executor serves the HTTP API the router calls to resolve a function
+// to a backend URL.
+type Executor struct {
+	fissionNamespace    string
+	functionNamespace   string
+	envBuilderNamespace string
+	backends            map[string]Backend
+}
+
+// StartExecutor registers the executor's backends - currently just
+// wasm, since poolmgr and newdeploy live outside this snapshot - and
+// serves the executor API on port.
+func StartExecutor(fissionNamespace, functionNamespace, envBuilderNamespace string, port int, healthRegistry *health.Registry) error {
+	wasmBackend := newWasmBackend(newWasmHost(functionNamespace))
+	e := &Executor{
+		fissionNamespace:    fissionNamespace,
+		functionNamespace:   functionNamespace,
+		envBuilderNamespace: envBuilderNamespace,
+		backends: map[string]Backend{
+			RuntimeWasm: wasmBackend,
+		},
+	}
+
+	healthRegistry.RegisterReadiness("wasm-pool", wasmBackend.pool.Ready)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/getServiceForFunction", e.handleGetServiceForFunction)
+
+	return http.ListenAndServe(fmt.Sprintf(":%v", port), mux)
+}
+
+// wasmRuntimeImage is the image running the wasmtime/wasmer sidecar
+// that serves a Host's /load and /invoke endpoints.
+const wasmRuntimeImage = "fission/wasm-runtime"
+
+// wasmRuntimePort is the port the runtime image listens on inside the
+// pod, matching httpHost's expectations in host.go.
+const wasmRuntimePort = 8888
+
+// newWasmHost returns a newHost func for wasm.NewPool: each call
+// creates a new warm runtime pod in functionNamespace, waits for it to
+// get an IP, and wraps it as a Host.
+func newWasmHost(functionNamespace string) func() (wasm.Host, error) {
+	return func() (wasm.Host, error) {
+		kubernetesClient, err := fission.GetKubernetesClient()
+		if err != nil {
+			return nil, err
+		}
+
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				GenerateName: "wasm-host-",
+				Labels: map[string]string{
+					"managed": "fission-wasm-executor",
+				},
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Name:  "wasm-runtime",
+						Image: wasmRuntimeImage,
+						Ports: []corev1.ContainerPort{
+							{ContainerPort: wasmRuntimePort},
+						},
+					},
+				},
+			},
+		}
+
+		created, err := kubernetesClient.CoreV1().Pods(functionNamespace).Create(context.Background(), pod, metav1.CreateOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("creating wasm host pod: %w", err)
+		}
+
+		podIP, err := waitForPodIP(kubernetesClient, functionNamespace, created.Name, 30*time.Second)
+		if err != nil {
+			return nil, err
+		}
+
+		return wasm.NewHTTPHost(fmt.Sprintf("http://%v:%v", podIP, wasmRuntimePort)), nil
+	}
+}
+
+// waitForPodIP polls name for a pod IP, the same way a caller would
+// wait for any newly scheduled pod to become addressable.
+func waitForPodIP(kubernetesClient kubernetes.Interface, namespace, name string, timeout time.Duration) (string, error) {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		pod, err := kubernetesClient.CoreV1().Pods(namespace).Get(context.Background(), name, metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+		if pod.Status.PodIP != "" {
+			return pod.Status.PodIP, nil
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	return "", fmt.Errorf("timed out waiting for pod %v to get an IP", name)
+}
+
+// getServiceForFunctionRequest is the body the router POSTs to
+// resolve a function to a backend URL before proxying to it.
+type getServiceForFunctionRequest struct {
+	FunctionName string      `json:"functionName"`
+	Runtime      string      `json:"runtime"`
+	WasmModule   wasm.Module `json:"wasmModule,omitempty"`
+}
+
+func (e *Executor) handleGetServiceForFunction(w http.ResponseWriter, r *http.Request) {
+	var req getServiceForFunctionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	backend, ok := e.backends[req.Runtime]
+	if !ok {
+		http.Error(w, fmt.Sprintf("no backend registered for runtime %q", req.Runtime), http.StatusNotImplemented)
+		return
+	}
+
+	serviceURL, err := backend.GetServiceForFunction(req.FunctionName, req.WasmModule)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	fmt.Fprint(w, serviceURL)
+}
@@ -0,0 +1,71 @@
+package wasm
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// httpHost talks to the wasmtime/wasmer-based sidecar's HTTP
+// interface running in a single pod, implementing Host.
+type httpHost struct {
+	podURL string
+	client *http.Client
+}
+
+// NewHTTPHost creates a Host backed by the wasm runtime pod reachable
+// at podURL (e.g. "http://<pod-ip>:8888").
+func NewHTTPHost(podURL string) Host {
+	return &httpHost{
+		podURL: podURL,
+		client: http.DefaultClient,
+	}
+}
+
+// Load implements Host by POSTing the module bytes and entrypoint to
+// the pod's /load endpoint.
+func (h *httpHost) Load(module Module) error {
+	req, err := http.NewRequest(http.MethodPost, h.podURL+"/load?entrypoint="+module.Entrypoint, bytes.NewReader(module.Bytes))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/wasm")
+	req.Header.Set("X-Fission-Wasm-Memory-Bytes", fmt.Sprintf("%d", module.Limits.MemoryBytes))
+	req.Header.Set("X-Fission-Wasm-Fuel-Units", fmt.Sprintf("%d", module.Limits.FuelUnits))
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("load failed: %v: %s", resp.Status, body)
+	}
+	return nil
+}
+
+// URL implements Host.
+func (h *httpHost) URL() string {
+	return h.podURL
+}
+
+// Invoke implements Host by POSTing the request body to the pod's
+// /invoke endpoint, which dispatches into the already-loaded module.
+func (h *httpHost) Invoke(request []byte) ([]byte, error) {
+	resp, err := h.client.Post(h.podURL+"/invoke", "application/octet-stream", bytes.NewReader(request))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("invoke failed: %v: %s", resp.Status, body)
+	}
+	return body, nil
+}
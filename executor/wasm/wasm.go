@@ -0,0 +1,206 @@
+// Package wasm implements a WebAssembly executor type, sitting next
+// to poolmgr and newdeploy. Instead of specializing a
+// language-specific container per function, it keeps a small pool of
+// pods running a wasmtime/wasmer-based host and loads compiled .wasm
+// modules into them, so cold start is module instantiation rather
+// than container startup.
+package wasm
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Limits bounds a single function invocation, configured on the
+// Environment CRD (spec.resources maps to MemoryBytes, spec.runtime
+// wasm-specific fields map to FuelUnits).
+type Limits struct {
+	MemoryBytes uint64
+	FuelUnits   uint64
+}
+
+// Module is a compiled WebAssembly function ready to be loaded into a
+// host pod.
+type Module struct {
+	Name       string
+	Entrypoint string
+	Bytes      []byte
+	Limits     Limits
+}
+
+// Host is the per-pod WASM runtime: it exposes Load to specialize a
+// pod with a module and Invoke to run it. Implementations talk HTTP
+// to the wasmtime/wasmer-based sidecar running in the pod.
+type Host interface {
+	Load(module Module) error
+	Invoke(request []byte) ([]byte, error)
+	// URL returns the address the router should send invocations to
+	// once this Host is specialized.
+	URL() string
+}
+
+// specialization tracks one in-flight GetHost call for a function, so
+// concurrent callers for the same function wait for the first load
+// instead of each specializing their own pod.
+type specialization struct {
+	done chan struct{}
+	host Host
+	err  error
+}
+
+// Pool manages a small set of warm Host pods and hands callers an
+// already-loaded Host for a function, loading the module on first
+// use. This mirrors poolmgr's generalized-pool-then-specialize
+// approach, but specialization is a module load instead of a
+// container spec patch.
+type Pool struct {
+	mu       sync.Mutex
+	newHost  func() (Host, error)
+	loaded   map[string]Host // function name -> specialized Host
+	inFlight map[string]*specialization
+	warm     []Host
+	capacity int
+	creating int // hosts an in-flight GetHost is creating or loading, reserved against capacity
+}
+
+// NewPool creates a Pool of at most capacity warm hosts, created on
+// demand with newHost.
+func NewPool(capacity int, newHost func() (Host, error)) *Pool {
+	return &Pool{
+		newHost:  newHost,
+		loaded:   make(map[string]Host),
+		inFlight: make(map[string]*specialization),
+		capacity: capacity,
+	}
+}
+
+// GetHost returns a Host with module already loaded, reusing a
+// previously specialized Host for the same function if one exists.
+// Both creating a new pod (newHost) and loading a module into it
+// (Load) are round-trips to the cluster/pod, so neither runs under
+// the pool lock: other functions' cold starts, and callers already
+// warm, aren't serialized behind either one.
+func (p *Pool) GetHost(module Module) (Host, error) {
+	p.mu.Lock()
+	if host, ok := p.loaded[module.Name]; ok {
+		p.mu.Unlock()
+		return host, nil
+	}
+
+	if spec, ok := p.inFlight[module.Name]; ok {
+		p.mu.Unlock()
+		<-spec.done
+		return spec.host, spec.err
+	}
+
+	spec := &specialization{done: make(chan struct{})}
+	p.inFlight[module.Name] = spec
+
+	host, fresh, err := p.reserveWarmHost()
+	if err == nil && !fresh {
+		// A warm host being loaded still counts against capacity.
+		p.creating++
+	}
+	p.mu.Unlock()
+
+	if err != nil {
+		p.failSpecialization(module.Name, spec, err)
+		return nil, err
+	}
+
+	if fresh {
+		host, err = p.newHost()
+		if err != nil {
+			p.mu.Lock()
+			p.creating--
+			p.mu.Unlock()
+			p.failSpecialization(module.Name, spec, err)
+			return nil, err
+		}
+	}
+
+	loadErr := host.Load(module)
+
+	p.mu.Lock()
+	p.creating--
+	delete(p.inFlight, module.Name)
+	if loadErr != nil {
+		// Loading failed; the host is still generalized, so return it
+		// to the warm set instead of leaking it.
+		p.warm = append(p.warm, host)
+	} else {
+		p.loaded[module.Name] = host
+	}
+	p.mu.Unlock()
+
+	if loadErr != nil {
+		spec.err = fmt.Errorf("loading module %v: %w", module.Name, loadErr)
+		close(spec.done)
+		return nil, spec.err
+	}
+
+	spec.host = host
+	close(spec.done)
+	return host, nil
+}
+
+// failSpecialization records err on spec and unblocks any callers
+// waiting on it, then clears the inFlight entry so a later GetHost
+// call for the same function can retry from scratch.
+func (p *Pool) failSpecialization(functionName string, spec *specialization, err error) {
+	spec.err = err
+	close(spec.done)
+	p.mu.Lock()
+	delete(p.inFlight, functionName)
+	p.mu.Unlock()
+}
+
+// reserveWarmHost returns an already-warm Host if one is available.
+// Otherwise, if capacity allows it, it reserves a slot (counted in
+// creating) for the caller to fill by calling newHost() itself,
+// unlocked, and returns fresh=true. The reservation keeps a second
+// concurrent caller from also deciding there's room for a new host.
+func (p *Pool) reserveWarmHost() (host Host, fresh bool, err error) {
+	if len(p.warm) > 0 {
+		host = p.warm[len(p.warm)-1]
+		p.warm = p.warm[:len(p.warm)-1]
+		return host, false, nil
+	}
+	if len(p.loaded)+len(p.warm)+p.creating >= p.capacity {
+		return nil, false, fmt.Errorf("wasm host pool exhausted (capacity %v)", p.capacity)
+	}
+	p.creating++
+	return nil, true, nil
+}
+
+// Release returns a function's Host to the warm set, e.g. when the
+// function is deleted or idles out, so the pod can be respecialized
+// for a different function.
+func (p *Pool) Release(functionName string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	host, ok := p.loaded[functionName]
+	if !ok {
+		return
+	}
+	delete(p.loaded, functionName)
+	p.warm = append(p.warm, host)
+}
+
+// Ready reports whether the pool can currently hand out a host for a
+// new function - either an already-warm one or, if under capacity, a
+// freshly created one. A pool pinned at capacity by already-loaded or
+// in-progress hosts can't specialize anything else until one is
+// Released, which a readiness probe should surface.
+func (p *Pool) Ready() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.warm) > 0 {
+		return nil
+	}
+	if len(p.loaded)+p.creating < p.capacity {
+		return nil
+	}
+	return fmt.Errorf("wasm host pool exhausted (capacity %v)", p.capacity)
+}
@@ -0,0 +1,117 @@
+package wasm
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeHost struct {
+	id string
+}
+
+func (f *fakeHost) Load(module Module) error          { return nil }
+func (f *fakeHost) Invoke(req []byte) ([]byte, error) { return nil, nil }
+func (f *fakeHost) URL() string                       { return f.id }
+
+// TestGetHostDedupesConcurrentSpecialization makes sure two concurrent
+// GetHost calls for the same function share a single newHost/Load,
+// instead of each racing to specialize its own pod.
+func TestGetHostDedupesConcurrentSpecialization(t *testing.T) {
+	var created int32
+	release := make(chan struct{})
+
+	pool := NewPool(4, func() (Host, error) {
+		n := atomic.AddInt32(&created, 1)
+		<-release // simulate a slow pod-creation round trip
+		return &fakeHost{id: fmt.Sprintf("host-%d", n)}, nil
+	})
+
+	const callers = 5
+	results := make(chan Host, callers)
+	var wg sync.WaitGroup
+	wg.Add(callers)
+	for i := 0; i < callers; i++ {
+		go func() {
+			defer wg.Done()
+			host, err := pool.GetHost(Module{Name: "fn"})
+			if err != nil {
+				t.Errorf("GetHost: %v", err)
+				return
+			}
+			results <- host
+		}()
+	}
+
+	// Give every goroutine a chance to reach newHost/inFlight before
+	// unblocking it, so they all observe the same in-flight specialization.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	if got := atomic.LoadInt32(&created); got != 1 {
+		t.Fatalf("newHost called %d times, want exactly 1", got)
+	}
+
+	var first Host
+	for host := range results {
+		if first == nil {
+			first = host
+			continue
+		}
+		if host != first {
+			t.Fatalf("concurrent callers got different Hosts for the same function")
+		}
+	}
+}
+
+// TestGetHostDoesNotBlockOnUnrelatedColdStart checks that a GetHost
+// call for an already-loaded function returns immediately even while
+// another function is still being specialized (the lock-scope bug
+// this pool was built to avoid).
+func TestGetHostDoesNotBlockOnUnrelatedColdStart(t *testing.T) {
+	slowRelease := make(chan struct{})
+	var which int32
+
+	pool := NewPool(4, func() (Host, error) {
+		n := atomic.AddInt32(&which, 1)
+		if n == 1 {
+			// The first host (for "slow") blocks until released.
+			<-slowRelease
+		}
+		return &fakeHost{id: fmt.Sprintf("host-%d", n)}, nil
+	})
+
+	slowDone := make(chan struct{})
+	go func() {
+		defer close(slowDone)
+		if _, err := pool.GetHost(Module{Name: "slow"}); err != nil {
+			t.Errorf("GetHost(slow): %v", err)
+		}
+	}()
+
+	// Give the "slow" goroutine time to reserve its slot and enter
+	// newHost before asking for an unrelated function.
+	time.Sleep(20 * time.Millisecond)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := pool.GetHost(Module{Name: "fast"})
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("GetHost(fast): %v", err)
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatal("GetHost(fast) blocked behind the in-flight cold start for \"slow\"")
+	}
+
+	close(slowRelease)
+	<-slowDone
+}
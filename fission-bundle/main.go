@@ -1,66 +1,239 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
 	"log"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
 	"strconv"
+	"time"
 
 	"go.opencensus.io/exporter/jaeger"
 	"go.opencensus.io/trace"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
 	docopt "github.com/docopt/docopt-go"
 
 	"github.com/fission/fission"
 	"github.com/fission/fission/buildermgr"
+	"github.com/fission/fission/configsource"
 	"github.com/fission/fission/controller"
 	"github.com/fission/fission/executor"
+	"github.com/fission/fission/health"
 	"github.com/fission/fission/kubewatcher"
+	"github.com/fission/fission/logsvc"
 	"github.com/fission/fission/mqtrigger"
 	"github.com/fission/fission/router"
 	"github.com/fission/fission/storagesvc"
+	"github.com/fission/fission/supervisor"
 	"github.com/fission/fission/timer"
 )
 
-func runController(port int) {
-	controller.Start(port, false)
-	log.Fatalf("Error: Controller exited.")
+// defaultHealthPorts is used when --healthPort isn't specified; each
+// subsystem gets its own default so they don't collide when several
+// are started in the same fission-bundle invocation.
+var defaultHealthPorts = map[string]int{
+	"controller":  8081,
+	"router":      8082,
+	"executor":    8083,
+	"kubewatcher": 8084,
+	"timer":       8085,
+	"mqt":         8086,
+	"storagesvc":  8087,
+	"builderMgr":  8088,
+	"logger":      8089,
 }
 
-func runRouter(port int, executorUrl string) {
-	router.Start(port, executorUrl)
-	log.Fatalf("Error: Router exited.")
+func serveHealth(healthPort int, registry *health.Registry) {
+	serveHealthAndRoutes(healthPort, registry, nil)
 }
 
-func runExecutor(port int, fissionNamespace, functionNamespace, envBuilderNamespace string) {
-	err := executor.StartExecutor(fissionNamespace, functionNamespace, envBuilderNamespace, port)
-	if err != nil {
-		log.Fatalf("Error starting executor: %v", err)
+// serveHealthAndRoutes serves registry's health endpoints on
+// healthPort alongside any extra routes a subsystem wants on the same
+// listener (e.g. logsvc's REST log API).
+func serveHealthAndRoutes(healthPort int, registry *health.Registry, extra map[string]http.Handler) {
+	mux := http.NewServeMux()
+	mux.Handle("/", registry.Handler())
+	for pattern, handler := range extra {
+		mux.Handle(pattern, handler)
 	}
+
+	go func() {
+		addr := fmt.Sprintf(":%v", healthPort)
+		err := http.ListenAndServe(addr, mux)
+		if err != nil {
+			// Logged, not fatal: a supervised restart re-runs this on
+			// a port the earlier listener still holds.
+			log.Printf("Error serving health checks on %v: %v", addr, err)
+		}
+	}()
 }
 
-func runKubeWatcher(routerUrl string) {
-	err := kubewatcher.Start(routerUrl)
-	if err != nil {
-		log.Fatalf("Error starting kubewatcher: %v", err)
-	}
+// Each runX function below is supervised: rather than calling
+// log.Fatalf and killing the whole pod, it returns an error (or lets a
+// panic propagate) so that supervisor.Supervisor can log it and
+// restart just that subsystem with backoff.
+
+// configSourceOpts bundles the optional non-CRD config sources the
+// controller can reconcile alongside the Kubernetes API, one per
+// --configFile/--configURL/--configGitURL flag supplied.
+type configSourceOpts struct {
+	fileDir       string
+	fileFrequency time.Duration
+	url           string
+	urlFrequency  time.Duration
+	gitURL        string
+	gitBranch     string
+	gitLocalPath  string
+	gitSubdir     string
+	gitFrequency  time.Duration
 }
 
-func runTimer(routerUrl string) {
-	err := timer.Start(routerUrl)
-	if err != nil {
-		log.Fatalf("Error starting timer: %v", err)
+func (o configSourceOpts) sources() []configsource.Source {
+	var sources []configsource.Source
+	if o.fileDir != "" {
+		sources = append(sources, configsource.NewFileSource(o.fileDir, o.fileFrequency))
 	}
+	if o.url != "" {
+		sources = append(sources, configsource.NewURLSource(o.url, o.urlFrequency))
+	}
+	if o.gitURL != "" {
+		sources = append(sources, configsource.NewGitSource(o.gitURL, o.gitBranch, o.gitLocalPath, o.gitSubdir, o.gitFrequency))
+	}
+	return sources
 }
 
-func runMessageQueueMgr(routerUrl string) {
-	err := messagequeue.Start(routerUrl)
-	if err != nil {
-		log.Fatalf("Error starting timer: %v", err)
+func runController(stopCh <-chan struct{}, sup *supervisor.Supervisor, port, healthPort int, configOpts configSourceOpts) error {
+	registry := health.NewRegistry()
+	registerKubeAPIServerCheck(registry)
+	registry.RegisterLiveness("not-crash-looping", sup.LivenessCheck("controller"))
+	serveHealth(healthPort, registry)
+
+	sources := configOpts.sources()
+	if len(sources) > 0 {
+		merger := configsource.NewMerger(100, sources...)
+		go func() {
+			err := merger.Start(stopCh)
+			if err != nil {
+				log.Printf("config source merger exited: %v", err)
+			}
+		}()
+		// controller.StartWithConfigSources reconciles merger.Events() into its CRD
+		// store, annotating resources it didn't get from the
+		// apiserver with configsource.SourceAnnotation.
+		controller.StartWithConfigSources(port, false, merger.Events())
+		return fmt.Errorf("controller exited")
 	}
+
+	controller.Start(port, false)
+	return fmt.Errorf("controller exited")
+}
+
+func runRouter(stopCh <-chan struct{}, sup *supervisor.Supervisor, port, healthPort int, executorUrl string) error {
+	registry := health.NewRegistry()
+	registerURLReachableCheck(registry, "executor-url-resolves", executorUrl)
+	registry.RegisterLiveness("not-crash-looping", sup.LivenessCheck("router"))
+	serveHealth(healthPort, registry)
+	router.Start(port, executorUrl)
+	return fmt.Errorf("router exited")
+}
+
+func runExecutor(stopCh <-chan struct{}, sup *supervisor.Supervisor, port, healthPort int, fissionNamespace, functionNamespace, envBuilderNamespace string) error {
+	registry := health.NewRegistry()
+	registerKubeAPIServerCheck(registry)
+	registry.RegisterLiveness("not-crash-looping", sup.LivenessCheck("executor"))
+	serveHealth(healthPort, registry)
+	return executor.StartExecutor(fissionNamespace, functionNamespace, envBuilderNamespace, port, registry)
+}
+
+func runKubeWatcher(stopCh <-chan struct{}, sup *supervisor.Supervisor, healthPort int, routerUrl string) error {
+	registry := health.NewRegistry()
+	registerURLReachableCheck(registry, "router-url-resolves", routerUrl)
+	registry.RegisterLiveness("not-crash-looping", sup.LivenessCheck("kubewatcher"))
+	serveHealth(healthPort, registry)
+	return kubewatcher.Start(routerUrl)
+}
+
+func runTimer(stopCh <-chan struct{}, sup *supervisor.Supervisor, healthPort int, routerUrl string) error {
+	registry := health.NewRegistry()
+	registerURLReachableCheck(registry, "router-url-resolves", routerUrl)
+	registry.RegisterLiveness("not-crash-looping", sup.LivenessCheck("timer"))
+	serveHealth(healthPort, registry)
+	return timer.Start(routerUrl)
+}
+
+func runMessageQueueMgr(stopCh <-chan struct{}, sup *supervisor.Supervisor, healthPort int, routerUrl string) error {
+	registry := health.NewRegistry()
+	registerBrokerReachableCheck(registry)
+	registry.RegisterLiveness("not-crash-looping", sup.LivenessCheck("mqt"))
+	serveHealth(healthPort, registry)
+	return messagequeue.Start(routerUrl)
+}
+
+// registerBrokerReachableCheck registers a readiness check that the
+// message queue broker mqt connects to (MESSAGE_QUEUE_URL, e.g. a NATS
+// or Kafka endpoint) accepts TCP connections. That broker, not the
+// router, is mqt's critical dependency: it's what triggers actually
+// fire off of.
+func registerBrokerReachableCheck(registry *health.Registry) {
+	registry.RegisterReadiness("broker-reachable", func() error {
+		brokerUrl := os.Getenv("MESSAGE_QUEUE_URL")
+		if brokerUrl == "" {
+			return fmt.Errorf("MESSAGE_QUEUE_URL is not set")
+		}
+		u, err := url.Parse(brokerUrl)
+		if err != nil {
+			return err
+		}
+		conn, err := net.DialTimeout("tcp", u.Host, 2*time.Second)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	})
+}
+
+// registerKubeAPIServerCheck registers a readiness check that the
+// kube-apiserver is reachable, for subsystems (controller, executor)
+// that can't do anything useful without it.
+func registerKubeAPIServerCheck(registry *health.Registry) {
+	registry.RegisterReadiness("kube-apiserver-reachable", func() error {
+		kubernetesClient, err := fission.GetKubernetesClient()
+		if err != nil {
+			return err
+		}
+		_, err = kubernetesClient.CoreV1().Namespaces().List(context.Background(), metav1.ListOptions{Limit: 1})
+		return err
+	})
 }
 
-func runStorageSvc(port int, filePath string) {
+// registerURLReachableCheck registers a readiness check verifying url
+// resolves, for subsystems whose only job is to call another fission
+// service.
+func registerURLReachableCheck(registry *health.Registry, name, targetUrl string) {
+	registry.RegisterReadiness(name, func() error {
+		_, err := http.Get(targetUrl)
+		return err
+	})
+}
+
+func runStorageSvc(stopCh <-chan struct{}, sup *supervisor.Supervisor, port, healthPort int, filePath string) error {
+	registry := health.NewRegistry()
+	registry.RegisterReadiness("filesystem-writable", func() error {
+		probe, err := ioutil.TempFile(filePath, ".fission-healthcheck-")
+		if err != nil {
+			return err
+		}
+		probe.Close()
+		return os.Remove(probe.Name())
+	})
+	registry.RegisterLiveness("not-crash-looping", sup.LivenessCheck("storagesvc"))
+	serveHealth(healthPort, registry)
 	subdir := os.Getenv("SUBDIR")
 	if len(subdir) == 0 {
 		subdir = "fission-functions"
@@ -68,13 +241,47 @@ func runStorageSvc(port int, filePath string) {
 	enableArchivePruner := true
 	storagesvc.RunStorageService(storagesvc.StorageTypeLocal,
 		filePath, subdir, port, enableArchivePruner)
+	return fmt.Errorf("storage service exited")
 }
 
-func runBuilderMgr(storageSvcUrl string, envBuilderNamespace string) {
-	err := buildermgr.Start(storageSvcUrl, envBuilderNamespace)
+func runLogSvc(stopCh <-chan struct{}, sup *supervisor.Supervisor, healthPort int, functionNamespace, storageSvcUrl string) error {
+	kubernetesClient, err := fission.GetKubernetesClient()
 	if err != nil {
-		log.Fatalf("Error starting buildermgr: %v", err)
+		return err
 	}
+
+	sinks := []logsvc.Sink{logsvc.NewStorageSvcSink(storageSvcUrl)}
+	svc := logsvc.New(kubernetesClient, functionNamespace, sinks...)
+
+	registry := health.NewRegistry()
+	registry.RegisterReadiness("kube-apiserver-reachable", func() error {
+		_, err := kubernetesClient.CoreV1().Pods(functionNamespace).List(context.Background(), metav1.ListOptions{Limit: 1})
+		return err
+	})
+	registry.RegisterReadiness("storagesvc-reachable", func() error {
+		_, err := http.Get(storageSvcUrl)
+		return err
+	})
+	registry.RegisterLiveness("not-crash-looping", sup.LivenessCheck("logger"))
+	// logsvc.LogsHandler is mounted on this subsystem's own healthPort
+	// listener, not the controller's --controllerPort one: the
+	// controller package isn't part of this source tree, so the route
+	// can't be added to the listener controller.Start serves. Operators who want
+	// GET /v2/functions/{name}/logs reachable on the controller's port
+	// need to front controller and logger with a reverse proxy.
+	serveHealthAndRoutes(healthPort, registry, map[string]http.Handler{
+		logsvc.LogsPathPrefix: svc.LogsHandler(),
+	})
+
+	return svc.Start(stopCh)
+}
+
+func runBuilderMgr(stopCh <-chan struct{}, sup *supervisor.Supervisor, healthPort int, storageSvcUrl string, envBuilderNamespace string) error {
+	registry := health.NewRegistry()
+	registerURLReachableCheck(registry, "storagesvc-reachable", storageSvcUrl)
+	registry.RegisterLiveness("not-crash-looping", sup.LivenessCheck("builderMgr"))
+	serveHealth(healthPort, registry)
+	return buildermgr.Start(storageSvcUrl, envBuilderNamespace)
 }
 
 func getPort(portArg interface{}) int {
@@ -94,6 +301,17 @@ func getStringArgWithDefault(arg interface{}, defaultValue string) string {
 	}
 }
 
+func pollFrequencyArg(arg interface{}, defaultValue time.Duration) time.Duration {
+	if arg == nil {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(arg.(string))
+	if err != nil {
+		log.Fatalf("Error: invalid duration '%v'", arg)
+	}
+	return d
+}
+
 func registerTraceExporter(arguments map[string]interface{}) error {
 	collectorEndpoint := getStringArgWithDefault(arguments["--collectorEndpoint"], "")
 	if collectorEndpoint == "" {
@@ -119,6 +337,8 @@ func registerTraceExporter(arguments map[string]interface{}) error {
 		serviceName = "Fission-BuilderMgr"
 	} else if arguments["--storageServicePort"] != nil {
 		serviceName = "Fission-StorageSvc"
+	} else if arguments["--logger"] == true {
+		serviceName = "Fission-LogSvc"
 	}
 
 	exporter, err := jaeger.NewExporter(jaeger.Options{
@@ -165,15 +385,14 @@ Use it to start one or more of the fission servers:
  in the Kubernetes API resource object. It supports various storage
  backends.
 
+fission-bundle can also start several of these subsystems from a single
+invocation, e.g. --controllerPort together with --kubewatcher, to
+collapse sidecars in resource-constrained clusters. Each one runs
+under its own supervisor and is restarted independently on panic or
+error.
+
 Usage:
-  fission-bundle --controllerPort=<port> [--collectorEndpoint=<url>]
-  fission-bundle --routerPort=<port> [--executorUrl=<url>] [--collectorEndpoint=<url>]
-  fission-bundle --executorPort=<port> [--namespace=<namespace>] [--fission-namespace=<namespace>] [--collectorEndpoint=<url>]
-  fission-bundle --kubewatcher [--routerUrl=<url>] [--collectorEndpoint=<url>]
-  fission-bundle --storageServicePort=<port> --filePath=<filePath> [--collectorEndpoint=<url>]
-  fission-bundle --builderMgr [--storageSvcUrl=<url>] [--envbuilder-namespace=<namespace>] [--collectorEndpoint=<url>]
-  fission-bundle --timer [--routerUrl=<url>] [--collectorEndpoint=<url>]
-  fission-bundle --mqt   [--routerUrl=<url>] [--collectorEndpoint=<url>]
+  fission-bundle [--controllerPort=<port>] [--configFile=<dir>] [--configFileFrequency=<duration>] [--configURL=<url>] [--configURLFrequency=<duration>] [--configGitURL=<url>] [--configGitBranch=<branch>] [--configGitPath=<path>] [--configGitSubdir=<dir>] [--configGitFrequency=<duration>] [--routerPort=<port>] [--executorUrl=<url>] [--executorPort=<port>] [--namespace=<namespace>] [--fission-namespace=<namespace>] [--kubewatcher] [--routerUrl=<url>] [--timer] [--mqt] [--builderMgr] [--storageSvcUrl=<url>] [--envbuilder-namespace=<namespace>] [--storageServicePort=<port>] [--filePath=<filePath>] [--logger] [--healthPort=<port>] [--collectorEndpoint=<url>]
   fission-bundle --version
 Options:
   --collectorEndpoint=<url> Jaeger HTTP Thrift collector URL.
@@ -181,6 +400,7 @@ Options:
   --routerPort=<port>             Port that the router should listen on.
   --executorPort=<port>           Port that the executor should listen on.
   --storageServicePort=<port>     Port that the storage service should listen on.
+  --healthPort=<port>             Port to serve /healthz, /readyz and /livez on. Defaults to a per-service port.
   --executorUrl=<url>             Executor URL. Not required if --executorPort is specified.
   --routerUrl=<url>               Router URL.
   --etcdUrl=<etcdUrl>             Etcd URL.
@@ -191,6 +411,16 @@ Options:
   --timer                         Start Timer.
   --mqt                           Start message queue trigger.
   --builderMgr                    Start builder manager.
+  --logger                        Start the function log capture service. Serves GET /v2/functions/<name>/logs on its own healthPort listener, not on --controllerPort.
+  --configFile=<dir>               Directory of YAML manifests the controller should reconcile alongside the Kubernetes API.
+  --configFileFrequency=<duration> How often to re-poll --configFile. Defaults to 30s.
+  --configURL=<url>                HTTP endpoint serving a YAML manifest stream to reconcile.
+  --configURLFrequency=<duration>  How often to re-poll --configURL. Defaults to 30s.
+  --configGitURL=<url>             Git repository of YAML manifests to reconcile.
+  --configGitBranch=<branch>       Branch to track in --configGitURL. Defaults to 'master'.
+  --configGitPath=<path>           Local path to clone --configGitURL into. Defaults to '/tmp/fission-configsource-git'.
+  --configGitSubdir=<dir>          Subdirectory within --configGitURL holding manifests. Defaults to '.'.
+  --configGitFrequency=<duration>  How often to check --configGitURL for new commits. Defaults to 1m.
   --version                       Print version information
 `
 	version := fmt.Sprintf("Fission Bundle Version: %v", fission.BuildInfo().String())
@@ -212,42 +442,116 @@ Options:
 	routerUrl := getStringArgWithDefault(arguments["--routerUrl"], "http://router.fission")
 	storageSvcUrl := getStringArgWithDefault(arguments["--storageSvcUrl"], "http://storagesvc.fission")
 
+	healthPortFor := func(service string) int {
+		if arguments["--healthPort"] != nil {
+			return getPort(arguments["--healthPort"])
+		}
+		return defaultHealthPorts[service]
+	}
+
+	// sup supervises every subsystem requested on the command line.
+	// Each one is restarted independently with backoff if it panics
+	// or exits with an error, and since Run doesn't block, several
+	// subsystems can be started in the same fission-bundle invocation
+	// (e.g. --controllerPort together with --kubewatcher) instead of
+	// the old mutually-exclusive dispatch.
+	sup := supervisor.New()
+	started := false
+
 	if arguments["--controllerPort"] != nil {
 		port := getPort(arguments["--controllerPort"])
-		runController(port)
+		healthPort := healthPortFor("controller")
+		configOpts := configSourceOpts{
+			fileDir:       getStringArgWithDefault(arguments["--configFile"], ""),
+			fileFrequency: pollFrequencyArg(arguments["--configFileFrequency"], 30*time.Second),
+			url:           getStringArgWithDefault(arguments["--configURL"], ""),
+			urlFrequency:  pollFrequencyArg(arguments["--configURLFrequency"], 30*time.Second),
+			gitURL:        getStringArgWithDefault(arguments["--configGitURL"], ""),
+			gitBranch:     getStringArgWithDefault(arguments["--configGitBranch"], "master"),
+			gitLocalPath:  getStringArgWithDefault(arguments["--configGitPath"], "/tmp/fission-configsource-git"),
+			gitSubdir:     getStringArgWithDefault(arguments["--configGitSubdir"], "."),
+			gitFrequency:  pollFrequencyArg(arguments["--configGitFrequency"], time.Minute),
+		}
+		sup.Run("controller", func(stopCh <-chan struct{}) error {
+			return runController(stopCh, sup, port, healthPort, configOpts)
+		})
+		started = true
 	}
 
 	if arguments["--routerPort"] != nil {
 		port := getPort(arguments["--routerPort"])
-		runRouter(port, executorUrl)
+		healthPort := healthPortFor("router")
+		sup.Run("router", func(stopCh <-chan struct{}) error {
+			return runRouter(stopCh, sup, port, healthPort, executorUrl)
+		})
+		started = true
 	}
 
 	if arguments["--executorPort"] != nil {
 		port := getPort(arguments["--executorPort"])
-		runExecutor(port, fissionNs, functionNs, envBuilderNs)
+		healthPort := healthPortFor("executor")
+		sup.Run("executor", func(stopCh <-chan struct{}) error {
+			return runExecutor(stopCh, sup, port, healthPort, fissionNs, functionNs, envBuilderNs)
+		})
+		started = true
 	}
 
 	if arguments["--kubewatcher"] == true {
-		runKubeWatcher(routerUrl)
+		healthPort := healthPortFor("kubewatcher")
+		sup.Run("kubewatcher", func(stopCh <-chan struct{}) error {
+			return runKubeWatcher(stopCh, sup, healthPort, routerUrl)
+		})
+		started = true
 	}
 
 	if arguments["--timer"] == true {
-		runTimer(routerUrl)
+		healthPort := healthPortFor("timer")
+		sup.Run("timer", func(stopCh <-chan struct{}) error {
+			return runTimer(stopCh, sup, healthPort, routerUrl)
+		})
+		started = true
 	}
 
 	if arguments["--mqt"] == true {
-		runMessageQueueMgr(routerUrl)
+		healthPort := healthPortFor("mqt")
+		sup.Run("mqt", func(stopCh <-chan struct{}) error {
+			return runMessageQueueMgr(stopCh, sup, healthPort, routerUrl)
+		})
+		started = true
 	}
 
 	if arguments["--builderMgr"] == true {
-		runBuilderMgr(storageSvcUrl, envBuilderNs)
+		healthPort := healthPortFor("builderMgr")
+		sup.Run("builderMgr", func(stopCh <-chan struct{}) error {
+			return runBuilderMgr(stopCh, sup, healthPort, storageSvcUrl, envBuilderNs)
+		})
+		started = true
+	}
+
+	if arguments["--logger"] == true {
+		healthPort := healthPortFor("logger")
+		sup.Run("logger", func(stopCh <-chan struct{}) error {
+			return runLogSvc(stopCh, sup, healthPort, functionNs, storageSvcUrl)
+		})
+		started = true
 	}
 
 	if arguments["--storageServicePort"] != nil {
+		if arguments["--filePath"] == nil {
+			log.Fatalf("Error: --filePath is required with --storageServicePort")
+		}
 		port := getPort(arguments["--storageServicePort"])
 		filePath := arguments["--filePath"].(string)
-		runStorageSvc(port, filePath)
+		healthPort := healthPortFor("storagesvc")
+		sup.Run("storagesvc", func(stopCh <-chan struct{}) error {
+			return runStorageSvc(stopCh, sup, port, healthPort, filePath)
+		})
+		started = true
+	}
+
+	if !started {
+		log.Fatalf("Error: no subsystem requested, see --help for usage")
 	}
 
-	select {}
+	sup.Wait()
 }
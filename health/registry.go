@@ -0,0 +1,107 @@
+// Package health provides a small registry that Fission's subsystems
+// (router, executor, buildermgr, ...) use to publish liveness and
+// readiness probes, and an HTTP handler that serves them in the same
+// style as the Kubernetes scheduler's /healthz, /readyz and /livez
+// endpoints.
+package health
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// CheckFunc reports the health of a single aspect of a subsystem. A
+// non-nil error marks the check as failing.
+type CheckFunc func() error
+
+// Registry collects named liveness and readiness checks for a single
+// fission-bundle subsystem and serves them over HTTP.
+type Registry struct {
+	mu        sync.RWMutex
+	liveness  map[string]CheckFunc
+	readiness map[string]CheckFunc
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		liveness:  make(map[string]CheckFunc),
+		readiness: make(map[string]CheckFunc),
+	}
+}
+
+// RegisterLiveness adds a named liveness check. A failing liveness
+// check means the process is wedged and should be restarted.
+func (r *Registry) RegisterLiveness(name string, check CheckFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.liveness[name] = check
+}
+
+// RegisterReadiness adds a named readiness check. A failing readiness
+// check means the process is up but shouldn't receive traffic yet.
+func (r *Registry) RegisterReadiness(name string, check CheckFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.readiness[name] = check
+}
+
+func (r *Registry) runChecks(checks map[string]CheckFunc) map[string]error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	results := make(map[string]error, len(checks))
+	for name, check := range checks {
+		results[name] = check()
+	}
+	return results
+}
+
+func writeResults(w http.ResponseWriter, results map[string]error) {
+	failed := false
+	for _, err := range results {
+		if err != nil {
+			failed = true
+			break
+		}
+	}
+
+	if failed {
+		w.WriteHeader(http.StatusInternalServerError)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	for name, err := range results {
+		if err != nil {
+			fmt.Fprintf(w, "[-]%s failed: %v\n", name, err)
+		} else {
+			fmt.Fprintf(w, "[+]%s ok\n", name)
+		}
+	}
+}
+
+// Handler returns an http.Handler serving /healthz, /readyz and
+// /livez, mirroring the Kubernetes scheduler's health endpoints so the
+// Helm chart can wire these up as Kubernetes liveness/readiness
+// probes.
+func (r *Registry) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/livez", func(w http.ResponseWriter, req *http.Request) {
+		writeResults(w, r.runChecks(r.liveness))
+	})
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, req *http.Request) {
+		writeResults(w, r.runChecks(r.liveness))
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, req *http.Request) {
+		results := r.runChecks(r.liveness)
+		for name, err := range r.runChecks(r.readiness) {
+			results[name] = err
+		}
+		writeResults(w, results)
+	})
+
+	return mux
+}
@@ -0,0 +1,73 @@
+package logsvc
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LogsPathPrefix is the REST route this LogService serves, matching
+// GET /v2/functions/{name}/logs?since=<RFC3339>&tail=<N>.
+const LogsPathPrefix = "/v2/functions/"
+
+// LogsHandler returns an http.Handler serving buffered function logs
+// straight out of Buffer, so callers don't need to round-trip to a
+// Sink for recent lines.
+func (l *LogService) LogsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		function, ok := parseFunctionName(r.URL.Path)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		since, err := parseSince(r.URL.Query().Get("since"))
+		if err != nil {
+			http.Error(w, "invalid since: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		tail, err := parseTail(r.URL.Query().Get("tail"))
+		if err != nil {
+			http.Error(w, "invalid tail: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		lines := l.Buffer.Since(function, since, tail)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(lines)
+	})
+}
+
+// parseFunctionName extracts {name} from
+// /v2/functions/{name}/logs, returning ok=false if path doesn't
+// match.
+func parseFunctionName(path string) (string, bool) {
+	rest := strings.TrimPrefix(path, LogsPathPrefix)
+	if rest == path {
+		return "", false
+	}
+	rest = strings.TrimSuffix(rest, "/")
+	name := strings.TrimSuffix(rest, "/logs")
+	if name == rest || name == "" {
+		return "", false
+	}
+	return name, true
+}
+
+func parseSince(raw string) (time.Time, error) {
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, raw)
+}
+
+func parseTail(raw string) (int, error) {
+	if raw == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(raw)
+}
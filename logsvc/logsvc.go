@@ -0,0 +1,249 @@
+// Package logsvc implements a fission-bundle subsystem that tails the
+// stdout/stderr of function pods and makes the resulting log lines
+// available off-cluster, so operators don't need to run a separate
+// log forwarder (e.g. fluentbit) just to retrieve function logs.
+//
+// It watches functionNamespace for pods labeled by the executor,
+// follows their logs via the Kubernetes API, buffers the lines
+// per-function, and forwards them to one or more Sinks. LogsHandler
+// (handler.go) serves the buffered lines over its own REST endpoint,
+// mounted on logsvc's dedicated listener rather than the controller's
+// - the controller subsystem isn't part of this source tree, so this
+// package can't add a route to its listener. An operator who wants
+// GET /v2/functions/{name}/logs reachable through the controller's own
+// port needs to front both with a reverse proxy, or the controller
+// needs to learn to proxy LogsPathPrefix through to this listener.
+package logsvc
+
+import (
+	"bufio"
+	"context"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// correlationIDEnvVar is the env var the router stamps onto a
+// function pod's spec with the request's OpenCensus trace ID, so log
+// lines can be joined back to the request that produced them.
+const correlationIDEnvVar = "X_FISSION_CORRELATION_ID"
+
+// functionLabel is stamped on every pod the executor creates, and is
+// how logsvc finds the pods it should tail.
+const functionLabel = "functionName"
+
+// Line is a single log line captured from a function pod, tagged with
+// enough context to join it back to a trace.
+type Line struct {
+	Function      string
+	PodName       string
+	Timestamp     time.Time
+	Text          string
+	CorrelationID string
+}
+
+// Sink receives log lines as they're captured. Implementations must
+// not block the tailer for long; slow sinks should buffer
+// internally.
+type Sink interface {
+	Write(Line) error
+}
+
+// Buffer keeps the last N lines per function in memory so LogsHandler
+// can serve recent logs without round-tripping to a sink.
+type Buffer struct {
+	mu       sync.RWMutex
+	perFnCap int
+	lines    map[string][]Line
+}
+
+// NewBuffer creates a Buffer retaining up to perFnCap lines per
+// function.
+func NewBuffer(perFnCap int) *Buffer {
+	return &Buffer{
+		perFnCap: perFnCap,
+		lines:    make(map[string][]Line),
+	}
+}
+
+// Write implements Sink.
+func (b *Buffer) Write(line Line) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	lines := append(b.lines[line.Function], line)
+	if len(lines) > b.perFnCap {
+		lines = lines[len(lines)-b.perFnCap:]
+	}
+	b.lines[line.Function] = lines
+	return nil
+}
+
+// Since returns up to tail lines for function written at or after
+// since. A zero since or zero tail means "no limit".
+func (b *Buffer) Since(function string, since time.Time, tail int) []Line {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	all := b.lines[function]
+	start := 0
+	if !since.IsZero() {
+		for i, l := range all {
+			if l.Timestamp.Before(since) {
+				start = i + 1
+			}
+		}
+	}
+	result := all[start:]
+	if tail > 0 && len(result) > tail {
+		result = result[len(result)-tail:]
+	}
+
+	out := make([]Line, len(result))
+	copy(out, result)
+	return out
+}
+
+// LogService tails function pods in functionNamespace and fans their
+// output out to a Buffer plus any configured Sinks.
+type LogService struct {
+	kubernetesClient  kubernetes.Interface
+	functionNamespace string
+	sinks             []Sink
+	Buffer            *Buffer
+
+	tailingMu sync.Mutex
+	tailing   map[types.UID]bool
+}
+
+// New creates a LogService. Call Start to begin tailing.
+func New(kubernetesClient kubernetes.Interface, functionNamespace string, sinks ...Sink) *LogService {
+	return &LogService{
+		kubernetesClient:  kubernetesClient,
+		functionNamespace: functionNamespace,
+		sinks:             sinks,
+		Buffer:            NewBuffer(1000),
+		tailing:           make(map[types.UID]bool),
+	}
+}
+
+// Start watches for function pods and tails each one's logs until
+// stopCh is closed.
+func (l *LogService) Start(stopCh <-chan struct{}) error {
+	watcher, err := l.kubernetesClient.CoreV1().Pods(l.functionNamespace).Watch(context.Background(), metav1.ListOptions{
+		LabelSelector: functionLabel,
+	})
+	if err != nil {
+		return err
+	}
+	defer watcher.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return nil
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				return nil
+			}
+			pod, ok := event.Object.(*corev1.Pod)
+			if !ok {
+				continue
+			}
+
+			if event.Type == "DELETED" {
+				l.tailingMu.Lock()
+				delete(l.tailing, pod.UID)
+				l.tailingMu.Unlock()
+				continue
+			}
+			if event.Type != "ADDED" && event.Type != "MODIFIED" {
+				continue
+			}
+
+			functionName := pod.Labels[functionLabel]
+			if functionName == "" {
+				continue
+			}
+
+			l.tailingMu.Lock()
+			alreadyTailing := l.tailing[pod.UID]
+			l.tailing[pod.UID] = true
+			l.tailingMu.Unlock()
+			if alreadyTailing {
+				continue
+			}
+
+			correlationID := correlationIDFromPod(pod)
+			go func(pod *corev1.Pod, functionName, correlationID string) {
+				defer func() {
+					l.tailingMu.Lock()
+					delete(l.tailing, pod.UID)
+					l.tailingMu.Unlock()
+				}()
+				l.tailPod(stopCh, pod.Namespace, pod.Name, functionName, correlationID)
+			}(pod, functionName, correlationID)
+		}
+	}
+}
+
+// correlationIDFromPod reads the correlation ID the router stamped
+// onto the pod's first container, if any.
+func correlationIDFromPod(pod *corev1.Pod) string {
+	if len(pod.Spec.Containers) == 0 {
+		return ""
+	}
+	for _, env := range pod.Spec.Containers[0].Env {
+		if env.Name == correlationIDEnvVar {
+			return env.Value
+		}
+	}
+	return ""
+}
+
+// tailPod follows a single pod's combined stdout/stderr until the
+// stream closes or stopCh fires, writing each line to the Buffer and
+// every configured Sink.
+func (l *LogService) tailPod(stopCh <-chan struct{}, podNamespace, podName, functionName, correlationID string) error {
+	req := l.kubernetesClient.CoreV1().Pods(podNamespace).GetLogs(podName, &corev1.PodLogOptions{Follow: true})
+	stream, err := req.Stream(context.Background())
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(stream)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	for {
+		select {
+		case <-stopCh:
+			return nil
+		case text, ok := <-lines:
+			if !ok {
+				return nil
+			}
+			line := Line{
+				Function:      functionName,
+				PodName:       podName,
+				Timestamp:     time.Now(),
+				Text:          text,
+				CorrelationID: correlationID,
+			}
+			l.Buffer.Write(line)
+			for _, sink := range l.sinks {
+				sink.Write(line)
+			}
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package logsvc
+
+import (
+	"bytes"
+	"fmt"
+	"log/syslog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileSink appends log lines to one file per function under dir, in
+// the simple "timestamp function pod: text" format used elsewhere in
+// fission's logs.
+type FileSink struct {
+	dir string
+
+	mu    sync.Mutex
+	files map[string]*os.File
+}
+
+// NewFileSink creates a FileSink writing under dir, which must
+// already exist.
+func NewFileSink(dir string) *FileSink {
+	return &FileSink{
+		dir:   dir,
+		files: make(map[string]*os.File),
+	}
+}
+
+// Write implements Sink.
+func (s *FileSink) Write(line Line) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.files[line.Function]
+	if !ok {
+		var err error
+		f, err = os.OpenFile(filepath.Join(s.dir, line.Function+".log"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		s.files[line.Function] = f
+	}
+
+	_, err := fmt.Fprintf(f, "%v %v %v: %v\n", line.Timestamp.Format(timeFormat), line.Function, line.PodName, line.Text)
+	return err
+}
+
+const timeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// SyslogSink forwards log lines to a syslog/fluentd-compatible
+// endpoint.
+type SyslogSink struct {
+	writer *syslog.Writer
+}
+
+// NewSyslogSink dials network at addr (e.g. "udp", "fluentd:5140")
+// and returns a Sink that forwards every log line there.
+func NewSyslogSink(network, addr string) (*SyslogSink, error) {
+	w, err := syslog.Dial(network, addr, syslog.LOG_INFO, "fission-logsvc")
+	if err != nil {
+		return nil, err
+	}
+	return &SyslogSink{writer: w}, nil
+}
+
+// Write implements Sink.
+func (s *SyslogSink) Write(line Line) error {
+	return s.writer.Info(fmt.Sprintf("%v[%v]: %v", line.Function, line.PodName, line.Text))
+}
+
+// storageSvcFlushSize is how many lines a function accumulates before
+// StorageSvcSink uploads them as one archive, to avoid one HTTP
+// request per log line.
+const storageSvcFlushSize = 500
+
+// StorageSvcSink batches log lines per function and uploads them
+// through fission's existing storagesvc backend, so the same
+// S3-compatible storage used for function archives can retain
+// function logs without running a separate object store.
+type StorageSvcSink struct {
+	storageSvcUrl string
+	client        *http.Client
+
+	mu      sync.Mutex
+	batches map[string][]Line
+}
+
+// NewStorageSvcSink creates a Sink that forwards log batches to the
+// storagesvc instance at storageSvcUrl.
+func NewStorageSvcSink(storageSvcUrl string) *StorageSvcSink {
+	return &StorageSvcSink{
+		storageSvcUrl: storageSvcUrl,
+		client:        http.DefaultClient,
+		batches:       make(map[string][]Line),
+	}
+}
+
+// Write implements Sink, buffering line in memory and flushing its
+// function's batch to storagesvc once it reaches storageSvcFlushSize.
+func (s *StorageSvcSink) Write(line Line) error {
+	s.mu.Lock()
+	batch := append(s.batches[line.Function], line)
+	if len(batch) < storageSvcFlushSize {
+		s.batches[line.Function] = batch
+		s.mu.Unlock()
+		return nil
+	}
+	delete(s.batches, line.Function)
+	s.mu.Unlock()
+
+	return s.upload(line.Function, batch)
+}
+
+func (s *StorageSvcSink) upload(function string, batch []Line) error {
+	var body bytes.Buffer
+	for _, line := range batch {
+		fmt.Fprintf(&body, "%v %v %v: %v\n", line.Timestamp.Format(timeFormat), line.Function, line.PodName, line.Text)
+	}
+
+	url := fmt.Sprintf("%v/v1/archive?name=%v-logs", s.storageSvcUrl, function)
+	resp, err := s.client.Post(url, "text/plain", &body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("storagesvc upload for %v failed: %v", function, resp.Status)
+	}
+	return nil
+}
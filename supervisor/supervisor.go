@@ -0,0 +1,170 @@
+// Package supervisor runs fission-bundle's subsystems (router,
+// executor, kubewatcher, ...) under crash recovery, modeled on
+// Kubernetes' util.HandleCrash and util.Until. A panic in a
+// supervised subsystem is logged with its stack trace instead of
+// taking down the whole pod, and the subsystem is restarted with
+// exponential backoff. This also lets a single fission-bundle
+// invocation run several subsystems side by side instead of exactly
+// one, so operators can collapse sidecars in resource-constrained
+// clusters.
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opencensus.io/trace"
+)
+
+// Func is a subsystem entry point. It should run until stopCh is
+// closed, or return early if it hits an unrecoverable error.
+type Func func(stopCh <-chan struct{}) error
+
+const (
+	minBackoff = 1 * time.Second
+	maxBackoff = 1 * time.Minute
+)
+
+var restarts = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "fission_supervisor_subsystem_restarts_total",
+		Help: "Number of times a fission-bundle subsystem has exited under supervision (panic, error or return).",
+	},
+	[]string{"subsystem"},
+)
+
+func init() {
+	prometheus.MustRegister(restarts)
+}
+
+// Supervisor runs a set of named subsystems concurrently, restarting
+// any that panic or return an error, with exponential backoff between
+// restarts.
+type Supervisor struct {
+	wg     sync.WaitGroup
+	stopCh chan struct{}
+
+	mu      sync.Mutex
+	backoff map[string]time.Duration // subsystem name -> its current restart backoff
+}
+
+// New creates a Supervisor. Call Run for each subsystem to supervise,
+// then Wait to block until they've all stopped (which normally only
+// happens after Stop is called).
+func New() *Supervisor {
+	return &Supervisor{
+		stopCh:  make(chan struct{}),
+		backoff: make(map[string]time.Duration),
+	}
+}
+
+// Run starts fn under supervision in its own goroutine and returns
+// immediately.
+func (s *Supervisor) Run(name string, fn Func) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.runUntil(name, fn)
+	}()
+}
+
+// Wait blocks until every subsystem started with Run has exited.
+func (s *Supervisor) Wait() {
+	s.wg.Wait()
+}
+
+// Stop signals every supervised subsystem to shut down.
+func (s *Supervisor) Stop() {
+	close(s.stopCh)
+}
+
+func (s *Supervisor) runUntil(name string, fn Func) {
+	backoff := minBackoff
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		default:
+		}
+
+		ranCleanly := s.runOnce(name, fn)
+
+		restarts.WithLabelValues(name).Inc()
+
+		// Always wait at least minBackoff before restarting, even
+		// after a clean return: fn is expected to run until stopCh
+		// closes, so returning on its own - cleanly or not - is
+		// unexpected and restarting it instantly would busy-loop if
+		// it keeps returning right away.
+		wait := backoff
+		if ranCleanly {
+			backoff = minBackoff
+			wait = minBackoff
+		} else {
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+
+		s.mu.Lock()
+		s.backoff[name] = backoff
+		s.mu.Unlock()
+
+		select {
+		case <-s.stopCh:
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// LivenessCheck returns a health.CheckFunc-shaped function reporting
+// whether name is crash-looping: backed off past minBackoff because
+// runOnce keeps returning quickly instead of running until stopCh
+// closes. A subsystem wires this into its own health.Registry so
+// /healthz and /livez reflect real restart state instead of always
+// passing.
+func (s *Supervisor) LivenessCheck(name string) func() error {
+	return func() error {
+		s.mu.Lock()
+		backoff := s.backoff[name]
+		s.mu.Unlock()
+
+		if backoff > minBackoff {
+			return fmt.Errorf("%v is crash-looping (current restart backoff %v)", name, backoff)
+		}
+		return nil
+	}
+}
+
+// runOnce runs fn a single time, recovering from any panic so the
+// caller can restart it instead of the process dying. It returns true
+// if fn returned without panicking or erroring.
+func (s *Supervisor) runOnce(name string, fn Func) (ranCleanly bool) {
+	_, span := trace.StartSpan(context.Background(), "supervisor."+name)
+	defer span.End()
+
+	defer func() {
+		if r := recover(); r != nil {
+			span.Annotate([]trace.Attribute{
+				trace.StringAttribute("service", name),
+				trace.StringAttribute("panic", "true"),
+			}, "panic recovered")
+			log.Printf("recovered panic in %v: %v\n%s", name, r, debug.Stack())
+			ranCleanly = false
+		}
+	}()
+
+	err := fn(s.stopCh)
+	if err != nil {
+		log.Printf("%v exited with error: %v", name, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,94 @@
+package supervisor
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestCleanReturnStillWaitsMinBackoff guards against runUntil
+// busy-looping when fn returns without error: every restart, even
+// after a clean return, must be at least minBackoff apart.
+func TestCleanReturnStillWaitsMinBackoff(t *testing.T) {
+	s := New()
+
+	var mu sync.Mutex
+	var calls []time.Time
+	done := make(chan struct{})
+
+	s.Run("clean", func(stopCh <-chan struct{}) error {
+		mu.Lock()
+		calls = append(calls, time.Now())
+		n := len(calls)
+		mu.Unlock()
+		if n >= 3 {
+			select {
+			case <-done:
+			default:
+				close(done)
+			}
+		}
+		return nil
+	})
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for 3 restarts of a cleanly-returning subsystem")
+	}
+	s.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	for i := 1; i < len(calls); i++ {
+		gap := calls[i].Sub(calls[i-1])
+		if gap < minBackoff {
+			t.Fatalf("restart %d fired %v after the previous one, want >= minBackoff (%v)", i, gap, minBackoff)
+		}
+	}
+}
+
+// TestLivenessCheckReflectsBackoff verifies LivenessCheck passes
+// before any restart and fails once a panic has backed the subsystem
+// off past minBackoff, i.e. it is crash-looping.
+func TestLivenessCheckReflectsBackoff(t *testing.T) {
+	s := New()
+
+	var failures int32
+	unblock := make(chan struct{})
+	restarted := make(chan struct{}, 10)
+
+	s.Run("flaky", func(stopCh <-chan struct{}) error {
+		select {
+		case <-unblock:
+		case <-stopCh:
+			return nil
+		}
+		restarted <- struct{}{}
+		failures++
+		if failures < 3 {
+			panic("boom")
+		}
+		return nil
+	})
+
+	check := s.LivenessCheck("flaky")
+	if err := check(); err != nil {
+		t.Fatalf("expected no crash-loop before any restart, got: %v", err)
+	}
+
+	close(unblock)
+	select {
+	case <-restarted:
+	case <-time.After(2 * time.Second):
+		t.Fatal("subsystem never ran")
+	}
+
+	// Give runUntil a moment to record the post-panic backoff.
+	time.Sleep(100 * time.Millisecond)
+	if err := check(); err == nil {
+		t.Fatal("expected LivenessCheck to report crash-looping after a panic, got nil error")
+	}
+
+	s.Stop()
+}